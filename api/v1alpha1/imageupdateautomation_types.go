@@ -0,0 +1,274 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+)
+
+// UpdateStrategyName is the type for names of update strategies.
+type UpdateStrategyName string
+
+const (
+	// UpdateStrategySetters is the name of the update strategy that
+	// uses setter markers in the manifests to figure out which images
+	// to update.
+	UpdateStrategySetters UpdateStrategyName = "Setters"
+	// UpdateStrategyKustomize is the name of the update strategy that
+	// edits the `images:` field of `kustomization.yaml` files to
+	// update images.
+	UpdateStrategyKustomize UpdateStrategyName = "Kustomize"
+)
+
+// UpdateStrategy is a union of the various strategies for updating
+// the git repository.
+type UpdateStrategy struct {
+	// Strategy names the strategy to be used.
+	// +kubebuilder:validation:Enum=Setters;Kustomize
+	Strategy UpdateStrategyName `json:"strategy"`
+	// Path gives the directory, relative to the root of the checked
+	// out repository, in which to look for files to update. If
+	// omitted, the whole repository is searched.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// GitCheckoutSpec specifies the git ref and checkout strategy to use.
+type GitCheckoutSpec struct {
+	// GitRepositoryRef refers to the resource giving access details
+	// to a git repository to update.
+	GitRepositoryRef meta.LocalObjectReference `json:"gitRepositoryRef"`
+	// Branch gives the branch to clone from the git repository.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+}
+
+// CommitSpec specifies how to commit to the git repository.
+type CommitSpec struct {
+	// AuthorName gives the name to provide when making a commit.
+	// +optional
+	AuthorName string `json:"authorName,omitempty"`
+	// AuthorEmail gives the email to provide when making a commit.
+	// +optional
+	AuthorEmail string `json:"authorEmail,omitempty"`
+	// MessageTemplate provides a template for the commit message,
+	// into which will be interpolated the details of the change
+	// made. This is a text/template template taking as its context
+	// an object with fields `.Updated.Images` (each with `.Name`,
+	// `.OldTag`, `.NewTag` and `.Policy`), `.Updated.Files` (the list
+	// of files changed), and `.AutomationObject` (the
+	// ImageUpdateAutomation's namespaced name).
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+	// MessageTemplateStrict requires every field referenced by
+	// MessageTemplate to resolve to a known value; by default, a
+	// template referencing a field that doesn't exist in the
+	// `.Updated`/`.AutomationObject` context renders "<no value>"
+	// rather than failing. A malformed template always fails,
+	// regardless of this setting.
+	// +optional
+	MessageTemplateStrict bool `json:"messageTemplateStrict,omitempty"`
+	// SigningKey references a Secret containing a `git.asc` armored
+	// PGP private key used to sign commits made by this automation,
+	// and an optional `passphrase` key if the private key is
+	// encrypted. If omitted, commits are not signed.
+	// +optional
+	SigningKey *meta.LocalObjectReference `json:"signingKey,omitempty"`
+}
+
+// ImageUpdateAutomationSpec defines the desired state of ImageUpdateAutomation
+type ImageUpdateAutomationSpec struct {
+	// Checkout gives the parameters for cloning the git repository in
+	// which to write updates.
+	Checkout GitCheckoutSpec `json:"checkout"`
+	// Interval gives an lower bound for how often the automation
+	// run should be attempted.
+	Interval metav1.Duration `json:"interval"`
+	// Update gives the specification of how to update the files in
+	// the repository. If omitted, it defaults to `{strategy:
+	// Setters}`.
+	// +kubebuilder:default:={strategy: Setters}
+	// +optional
+	Update *UpdateStrategy `json:"update,omitempty"`
+	// ImagePolicySelector narrows the ImagePolicies, in the same
+	// namespace as this object, that are considered when looking for
+	// an image to apply to a reference in the checked out files. If
+	// omitted, all ImagePolicies in the namespace are considered.
+	// +optional
+	ImagePolicySelector *metav1.LabelSelector `json:"imagePolicySelector,omitempty"`
+	// Commit specifies how to commit to the git repository.
+	Commit CommitSpec `json:"commit"`
+	// Push specifies how and where to push the commit, when the
+	// commit should not simply be pushed to the checkout branch.
+	// +optional
+	Push *PushSpec `json:"push,omitempty"`
+	// Suspend tells the controller to not run this automation, until
+	// it is unset (or set to false). Defaults to false.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// PushSpec specifies how and where to push commits.
+type PushSpec struct {
+	// Branch gives the name of the branch to push to. If this
+	// differs from Checkout.Branch, the branch is created, based on
+	// the checkout branch, before pushing.
+	Branch string `json:"branch"`
+	// PullRequest, if present, configures the controller to open (or
+	// update) a pull/merge request at the provider for the change,
+	// rather than relying on the Branch being merged by some other
+	// means.
+	// +optional
+	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
+}
+
+// PullRequestProvider names a Git hosting provider capable of hosting
+// pull (or merge) requests.
+type PullRequestProvider string
+
+const (
+	PullRequestProviderGitHub    PullRequestProvider = "github"
+	PullRequestProviderGitLab    PullRequestProvider = "gitlab"
+	PullRequestProviderGitea     PullRequestProvider = "gitea"
+	PullRequestProviderBitbucket PullRequestProvider = "bitbucket"
+)
+
+// PullRequestSpec configures the controller to open, or update, a
+// pull/merge request at the named provider once the push branch has
+// been updated.
+type PullRequestSpec struct {
+	// Provider names the Git hosting provider to talk to.
+	// +kubebuilder:validation:Enum=github;gitlab;gitea;bitbucket
+	Provider PullRequestProvider `json:"provider"`
+	// Address is the base URL of the provider's API, e.g.
+	// https://api.github.com.
+	Address string `json:"address"`
+	// SecretRef refers to a secret in the same namespace as this
+	// object, containing a `token` field used to authenticate with
+	// the provider's API.
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+	// MessageTemplate is rendered, using the same context as
+	// Commit.MessageTemplate, to produce the title and body of the
+	// pull/merge request. The first line becomes the title; the
+	// remainder becomes the body. If omitted, the commit message is
+	// used.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+	// Labels lists the labels to apply to the pull/merge request.
+	// Only the github and gitlab providers send them; the gitea and
+	// bitbucket providers ignore them.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+	// Reviewers lists the users to request a review from.
+	// +optional
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+// ImageUpdateAutomationStatus defines the observed state of ImageUpdateAutomation
+type ImageUpdateAutomationStatus struct {
+	// LastAutomationRunTime records the last time the controller ran
+	// this automation through to completion (even if no updates were
+	// made).
+	// +optional
+	LastAutomationRunTime *metav1.Time `json:"lastAutomationRunTime,omitempty"`
+	// LastPushCommit records the SHA1 of the last commit made by the
+	// controller, for this automation object.
+	// +optional
+	LastPushCommit string `json:"lastPushCommit,omitempty"`
+	// LastPushTime records the time of the last pushed change.
+	// +optional
+	LastPushTime *metav1.Time `json:"lastPushTime,omitempty"`
+	// LastPushBranch records the branch that the last push went to,
+	// when Push is configured with a branch.
+	// +optional
+	LastPushBranch string `json:"lastPushBranch,omitempty"`
+	// LastPullRequest records the pull/merge request opened, or
+	// updated, for the last push, when Push.PullRequest is
+	// configured.
+	// +optional
+	LastPullRequest *PullRequestStatus `json:"lastPullRequest,omitempty"`
+	// LastRunChecksum is a checksum of the inputs that determine
+	// whether a run of the automation would produce a change (the
+	// image policies in scope, the update strategy, how the commit is
+	// made and signed, how the result is pushed and opened as a
+	// pull/merge request, and the source revision). If a new run
+	// computes the same checksum, the controller skips the
+	// clone/apply/push cycle.
+	// +optional
+	LastRunChecksum string `json:"lastRunChecksum,omitempty"`
+	// LastRunResult records the outcome of the most recent run that
+	// performed an update.
+	// +optional
+	LastRunResult *UpdateRunResult `json:"lastRunResult,omitempty"`
+	// ObservedGeneration is the last observed generation.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions holds the conditions for the ImageUpdateAutomation.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// UpdateRunResult records the observable effect of applying an update
+// strategy to the checked out files.
+type UpdateRunResult struct {
+	// ChangedFiles lists the paths, relative to the root of the
+	// checked out repository, of the files that were mutated.
+	// +optional
+	ChangedFiles []string `json:"changedFiles,omitempty"`
+}
+
+// PullRequestStatus records the outcome of opening, or updating, a
+// pull/merge request at a provider.
+type PullRequestStatus struct {
+	URL    string `json:"url,omitempty"`
+	Number int    `json:"number,omitempty"`
+	State  string `json:"state,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description=""
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message",description=""
+
+// ImageUpdateAutomation is the Schema for the imageupdateautomations API
+type ImageUpdateAutomation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageUpdateAutomationSpec   `json:"spec,omitempty"`
+	Status ImageUpdateAutomationStatus `json:"status,omitempty"`
+}
+
+// GetStatusConditions returns a pointer to the Status.Conditions slice
+func (in *ImageUpdateAutomation) GetStatusConditions() *[]metav1.Condition {
+	return &in.Status.Conditions
+}
+
+// +kubebuilder:object:root=true
+
+// ImageUpdateAutomationList contains a list of ImageUpdateAutomation
+type ImageUpdateAutomationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageUpdateAutomation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageUpdateAutomation{}, &ImageUpdateAutomationList{})
+}
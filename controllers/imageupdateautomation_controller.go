@@ -0,0 +1,569 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-logr/logr"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1alpha1"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1alpha1"
+	"github.com/fluxcd/image-automation-controller/pkg/commitmessage"
+	"github.com/fluxcd/image-automation-controller/pkg/provider"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// ImageUpdateAutomationReconciler reconciles a ImageUpdateAutomation object
+type ImageUpdateAutomationReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imageupdateautomations,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imageupdateautomations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imagepolicies,verbs=get;list;watch
+
+func (r *ImageUpdateAutomationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("imageupdateautomation", req.NamespacedName)
+
+	var auto imagev1.ImageUpdateAutomation
+	if err := r.Get(ctx, req.NamespacedName, &auto); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if auto.Spec.Suspend {
+		log.Info("automation is suspended, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	patchAuto := auto.DeepCopy()
+	defer func() {
+		patchAuto.Status.ObservedGeneration = auto.Generation
+		if err := r.Status().Update(ctx, patchAuto); err != nil {
+			log.Error(err, "failed to update status")
+		}
+	}()
+
+	var origin sourcev1.GitRepository
+	gitRepoKey := types.NamespacedName{
+		Namespace: auto.GetNamespace(),
+		Name:      auto.Spec.Checkout.GitRepositoryRef.Name,
+	}
+	if err := r.Get(ctx, gitRepoKey, &origin); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("referenced git repository does not exist", "gitrepository", gitRepoKey)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	policies, err := r.policyMap(ctx, &auto)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	updateStrategy := imagev1.UpdateStrategy{Strategy: imagev1.UpdateStrategySetters}
+	if auto.Spec.Update != nil {
+		updateStrategy = *auto.Spec.Update
+	}
+	strategy := updateStrategy.Strategy
+
+	sourceRevision := ""
+	if origin.Status.Artifact != nil {
+		sourceRevision = origin.Status.Artifact.Revision
+	}
+
+	checksum := runChecksum(policies, updateStrategy, auto.Spec.Commit, auto.Spec.Push, sourceRevision)
+	now := metav1.Now()
+	if checksum == auto.Status.LastRunChecksum {
+		log.Info("no changes to source or image policies since last run, skipping")
+		patchAuto.Status.LastAutomationRunTime = &now
+		return ctrl.Result{RequeueAfter: auto.Spec.Interval.Duration}, nil
+	}
+
+	tmp, err := ioutil.TempDir("", "ial-"+auto.Namespace+"-"+auto.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	auth, err := r.loadTransportAuth(ctx, &origin)
+	if err != nil {
+		log.Error(err, "failed to configure git transport authentication")
+		return ctrl.Result{}, err
+	}
+
+	repo, wt, err := r.cloneAndCheckout(ctx, &origin, auto.Spec.Checkout.Branch, tmp, auth)
+	if err != nil {
+		log.Error(err, "failed to clone repository for update")
+		return ctrl.Result{}, err
+	}
+
+	updatePath := tmp
+	if auto.Spec.Update != nil && auto.Spec.Update.Path != "" {
+		updatePath = filepath.Join(tmp, auto.Spec.Update.Path)
+	}
+
+	var changes update.Result
+	switch strategy {
+	case imagev1.UpdateStrategySetters:
+		changes, err = update.UpdateWithSetters(tmp, updatePath, policies)
+	case imagev1.UpdateStrategyKustomize:
+		changes, err = update.UpdateWithKustomize(tmp, updatePath, policies)
+	default:
+		return ctrl.Result{}, fmt.Errorf("unknown update strategy %q", strategy)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var signingEntity *openpgp.Entity
+	if auto.Spec.Commit.SigningKey != nil {
+		signingEntity, err = r.loadSigningEntity(ctx, auto.Namespace, auto.Spec.Commit.SigningKey.Name)
+		if err != nil {
+			apimeta.SetStatusCondition(patchAuto.GetStatusConditions(), metav1.Condition{
+				Type:    meta.ReadyCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  "InvalidSigningKey",
+				Message: err.Error(),
+			})
+			return ctrl.Result{}, err
+		}
+	}
+
+	commitMsg, err := commitmessage.Render(auto.Spec.Commit.MessageTemplate, req.NamespacedName, changes, auto.Spec.Commit.MessageTemplateStrict)
+	if err != nil {
+		apimeta.SetStatusCondition(patchAuto.GetStatusConditions(), metav1.Condition{
+			Type:    meta.ReadyCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidCommitMessageTemplate",
+			Message: err.Error(),
+		})
+		return ctrl.Result{}, err
+	}
+
+	rev, pushBranch, err := commitAndPush(wt, repo, &auto, commitMsg, signingEntity, auth)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now = metav1.Now()
+	patchAuto.Status.LastAutomationRunTime = &now
+	patchAuto.Status.LastRunChecksum = checksum
+	if rev != "" {
+		patchAuto.Status.LastPushCommit = rev
+		patchAuto.Status.LastPushTime = &now
+		patchAuto.Status.LastRunResult = &imagev1.UpdateRunResult{ChangedFiles: changes.Files()}
+		apimeta.SetStatusCondition(patchAuto.GetStatusConditions(), metav1.Condition{
+			Type:    meta.ReadyCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Succeeded",
+			Message: "committed and pushed " + rev,
+		})
+
+		if auto.Spec.Push != nil && auto.Spec.Push.Branch != "" {
+			patchAuto.Status.LastPushBranch = pushBranch
+		}
+
+		if auto.Spec.Push != nil && auto.Spec.Push.PullRequest != nil {
+			result, err := r.openPullRequest(ctx, &origin, &auto, auto.Spec.Checkout.Branch, pushBranch, commitMsg, req.NamespacedName, changes)
+			if err != nil {
+				log.Error(err, "failed to open pull request")
+				return ctrl.Result{}, err
+			}
+			patchAuto.Status.LastPullRequest = result
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: auto.Spec.Interval.Duration}, nil
+}
+
+// openPullRequest opens, or updates, a pull/merge request for the
+// change just pushed to sourceBranch, to be merged into
+// targetBranch, using the provider configured in
+// auto.Spec.Push.PullRequest. Its title and body default to the
+// rendered commit message, unless PullRequest.MessageTemplate is
+// set, in which case that is rendered instead through the same
+// template engine used for commit messages.
+func (r *ImageUpdateAutomationReconciler) openPullRequest(ctx context.Context, origin *sourcev1.GitRepository, auto *imagev1.ImageUpdateAutomation, targetBranch, sourceBranch, commitMsg string, automationObject types.NamespacedName, changes update.Result) (*imagev1.PullRequestStatus, error) {
+	prSpec := auto.Spec.Push.PullRequest
+
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: auto.Namespace, Name: prSpec.SecretRef.Name}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		return nil, fmt.Errorf("referenced pull request secret %s not found: %w", secretKey, err)
+	}
+	token := string(secret.Data["token"])
+
+	owner, repoName, err := ownerAndRepo(origin.Spec.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := provider.NewClient(provider.Name(prSpec.Provider), prSpec.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	message := commitMsg
+	if prSpec.MessageTemplate != "" {
+		rendered, err := commitmessage.Render(prSpec.MessageTemplate, automationObject, changes, auto.Spec.Commit.MessageTemplateStrict)
+		if err != nil {
+			return nil, fmt.Errorf("parsing pull request message template: %w", err)
+		}
+		message = rendered
+	}
+	lines := strings.SplitN(message, "\n", 2)
+	title, body := lines[0], ""
+	if len(lines) > 1 {
+		body = lines[1]
+	}
+
+	result, err := client.CreateOrUpdatePullRequest(provider.Request{
+		Owner:        owner,
+		Repo:         repoName,
+		SourceBranch: sourceBranch,
+		TargetBranch: targetBranch,
+		Title:        title,
+		Body:         body,
+		Labels:       prSpec.Labels,
+		Reviewers:    prSpec.Reviewers,
+		Token:        token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &imagev1.PullRequestStatus{
+		URL:    result.URL,
+		Number: result.Number,
+		State:  result.State,
+	}, nil
+}
+
+// loadSigningEntity fetches the Secret named by keyName in namespace
+// and parses its `git.asc` data as an armored PGP private key,
+// decrypting it with the `passphrase` data if present. It returns a
+// clear error if the secret is missing, or the key cannot be parsed
+// or decrypted, so that callers can surface the problem rather than
+// silently producing an unsigned commit.
+func (r *ImageUpdateAutomationReconciler) loadSigningEntity(ctx context.Context, namespace, keyName string) (*openpgp.Entity, error) {
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: namespace, Name: keyName}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		return nil, fmt.Errorf("signing key secret %s not found: %w", secretKey, err)
+	}
+
+	armoredKey, ok := secret.Data["git.asc"]
+	if !ok {
+		return nil, fmt.Errorf("signing key secret %s does not have a 'git.asc' data field", secretKey)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse 'git.asc' in secret %s as an armored PGP key: %w", secretKey, err)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no PGP key found in secret %s", secretKey)
+	}
+	entity := entities[0]
+
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		passphrase, ok := secret.Data["passphrase"]
+		if !ok {
+			return nil, fmt.Errorf("private key in secret %s is encrypted, but no 'passphrase' data field was given", secretKey)
+		}
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("could not decrypt private key in secret %s: %w", secretKey, err)
+		}
+	}
+
+	return entity, nil
+}
+
+// ownerAndRepo does a best-effort extraction of the owner and
+// repository name from a git URL, e.g.
+// https://github.com/owner/repo.git -> ("owner", "repo").
+func ownerAndRepo(repoURL string) (string, string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", err
+	}
+	path := strings.TrimSuffix(strings.TrimPrefix(u.Path, "/"), ".git")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return "", "", fmt.Errorf("could not determine owner/repo from URL %q", repoURL)
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// cloneAndCheckout clones the repository given by the GitRepository's URL into the
+// directory at dir, checking out the given branch (the clone fails if
+// the branch does not exist at the remote), and returns the go-git repository handle
+// along with its worktree. Only the go-git implementation is
+// supported; an origin configured for any other implementation (e.g.
+// libgit2) is rejected rather than silently cloned with go-git.
+func (r *ImageUpdateAutomationReconciler) cloneAndCheckout(ctx context.Context, origin *sourcev1.GitRepository, branch, dir string, auth transport.AuthMethod) (*git.Repository, *git.Worktree, error) {
+	if impl := origin.Spec.GitImplementation; impl != "" && impl != sourcev1.GoGitImplementation {
+		return nil, nil, fmt.Errorf("gitImplementation %q is not supported; this controller only implements %q", impl, sourcev1.GoGitImplementation)
+	}
+
+	repo, err := git.Clone(memory.NewStorage(), osfs.New(dir), &git.CloneOptions{
+		URL:           origin.Spec.URL,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		Auth:          auth,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, wt, nil
+}
+
+// loadTransportAuth builds a go-git transport.AuthMethod from the
+// Secret referenced by origin.Spec.SecretRef, so that the clone and
+// push to origin can authenticate. It supports HTTP basic auth (a
+// `username`/`password` pair) and SSH public-key auth (an `identity`
+// private key, with an optional `known_hosts` used to verify the
+// remote host key). It returns (nil, nil) if no secret is referenced,
+// so that unauthenticated access (e.g. a public HTTP repository)
+// keeps working.
+func (r *ImageUpdateAutomationReconciler) loadTransportAuth(ctx context.Context, origin *sourcev1.GitRepository) (transport.AuthMethod, error) {
+	if origin.Spec.SecretRef == nil {
+		return nil, nil
+	}
+
+	var secret corev1.Secret
+	secretKey := types.NamespacedName{Namespace: origin.Namespace, Name: origin.Spec.SecretRef.Name}
+	if err := r.Get(ctx, secretKey, &secret); err != nil {
+		return nil, fmt.Errorf("referenced git repository secret %s not found: %w", secretKey, err)
+	}
+
+	if username, ok := secret.Data["username"]; ok {
+		return &githttp.BasicAuth{Username: string(username), Password: string(secret.Data["password"])}, nil
+	}
+
+	identity, ok := secret.Data["identity"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has neither a 'username' nor an 'identity' data field", secretKey)
+	}
+	publicKeys, err := gitssh.NewPublicKeys("git", identity, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse 'identity' in secret %s as an SSH private key: %w", secretKey, err)
+	}
+	if knownHosts, ok := secret.Data["known_hosts"]; ok {
+		callback, err := hostKeyCallback(knownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse 'known_hosts' in secret %s: %w", secretKey, err)
+		}
+		publicKeys.HostKeyCallback = callback
+	}
+	return publicKeys, nil
+}
+
+// hostKeyCallback builds an ssh.HostKeyCallback that verifies a
+// remote's host key against the given known_hosts data.
+func hostKeyCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	f, err := ioutil.TempFile("", "image-automation-known-hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(knownHosts); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	return knownhosts.New(f.Name())
+}
+
+// runChecksum computes a stable SHA-256 over the inputs that
+// determine whether a run of the automation would produce a change:
+// the resolved image for each policy, the update strategy (including
+// its configured path), how the commit is made (message template and
+// its strictness, and the signing key referenced, if any), how (and
+// whether) the result is pushed and opened as a pull/merge request,
+// and sourceRevision, the revision of the GitRepository's latest
+// artifact (empty if it has none). The clone itself checks out the
+// tip of the checkout branch, so a commit to that branch which is not
+// reflected in sourceRevision does not change the checksum.
+func runChecksum(policies map[string]string, strategy imagev1.UpdateStrategy, commit imagev1.CommitSpec, push *imagev1.PushSpec, sourceRevision string) string {
+	keys := make([]string, 0, len(policies))
+	for k := range policies {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "policy:%s=%s\n", k, policies[k])
+	}
+	fmt.Fprintf(h, "strategy=%s\n", strategy.Strategy)
+	fmt.Fprintf(h, "path=%s\n", strategy.Path)
+	fmt.Fprintf(h, "commitTemplate=%s\n", commit.MessageTemplate)
+	fmt.Fprintf(h, "commitTemplateStrict=%t\n", commit.MessageTemplateStrict)
+	if commit.SigningKey != nil {
+		fmt.Fprintf(h, "signingKey=%s\n", commit.SigningKey.Name)
+	}
+	if push != nil {
+		fmt.Fprintf(h, "pushBranch=%s\n", push.Branch)
+		if pr := push.PullRequest; pr != nil {
+			fmt.Fprintf(h, "pullRequestProvider=%s\n", pr.Provider)
+			fmt.Fprintf(h, "pullRequestAddress=%s\n", pr.Address)
+			fmt.Fprintf(h, "pullRequestSecretRef=%s\n", pr.SecretRef.Name)
+			fmt.Fprintf(h, "pullRequestTemplate=%s\n", pr.MessageTemplate)
+			fmt.Fprintf(h, "pullRequestLabels=%s\n", strings.Join(pr.Labels, ","))
+			fmt.Fprintf(h, "pullRequestReviewers=%s\n", strings.Join(pr.Reviewers, ","))
+		}
+	}
+	fmt.Fprintf(h, "source=%s\n", sourceRevision)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// policyMap builds a lookup, keyed by "namespace:name", of the
+// latest image for every ImagePolicy in the automation's namespace
+// that matches its ImagePolicySelector (all of them, if it has none).
+// Policies without a latest image are left out.
+func (r *ImageUpdateAutomationReconciler) policyMap(ctx context.Context, auto *imagev1.ImageUpdateAutomation) (map[string]string, error) {
+	listOpts := []client.ListOption{client.InNamespace(auto.Namespace)}
+	if auto.Spec.ImagePolicySelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(auto.Spec.ImagePolicySelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid imagePolicySelector: %w", err)
+		}
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	var policies imagev1_reflect.ImagePolicyList
+	if err := r.List(ctx, &policies, listOpts...); err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for _, p := range policies.Items {
+		if p.Status.LatestImage == "" {
+			continue
+		}
+		result[fmt.Sprintf("%s:%s", p.Namespace, p.Name)] = p.Status.LatestImage
+	}
+	return result, nil
+}
+
+// commitAndPush commits any pending changes in the worktree using
+// message as the commit message, signing the commit with
+// signingEntity if it is non-nil, then pushes the result either to
+// the checkout branch, or, if Push is configured with a different
+// branch, to that branch instead (leaving the checkout branch
+// untouched), authenticating with auth if it is non-nil. It returns
+// the new commit hash and the branch it was pushed to, or ("", "",
+// nil) if there was nothing to commit.
+func commitAndPush(wt *git.Worktree, repo *git.Repository, auto *imagev1.ImageUpdateAutomation, message string, signingEntity *openpgp.Entity, auth transport.AuthMethod) (string, string, error) {
+	status, err := wt.Status()
+	if err != nil {
+		return "", "", err
+	}
+	if status.IsClean() {
+		return "", "", nil
+	}
+
+	if _, err := wt.Add("."); err != nil {
+		return "", "", err
+	}
+
+	author := &object.Signature{
+		Name:  auto.Spec.Commit.AuthorName,
+		Email: auto.Spec.Commit.AuthorEmail,
+		When:  time.Now(),
+	}
+	if author.Name == "" {
+		author.Name = "fluxcdbot"
+	}
+
+	hash, err := wt.Commit(message, &git.CommitOptions{
+		Author:  author,
+		SignKey: signingEntity,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	targetBranch := auto.Spec.Checkout.Branch
+	if auto.Spec.Push != nil && auto.Spec.Push.Branch != "" {
+		targetBranch = auto.Spec.Push.Branch
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("+HEAD:refs/heads/%s", targetBranch))
+	if err := repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+	}); err != nil {
+		return "", "", err
+	}
+
+	return hash.String(), targetBranch, nil
+}
+
+func (r *ImageUpdateAutomationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&imagev1.ImageUpdateAutomation{}).
+		Complete(r)
+}
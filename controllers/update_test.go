@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -38,6 +40,8 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/otiai10/copy"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -310,6 +314,43 @@ var _ = Describe("ImageUpdateAutomation", func() {
 					})
 				})
 
+				It("does not push again when nothing has changed, but does once the policy changes", func() {
+					head, _ := localRepo.Head()
+					firstRun := head.Hash()
+
+					// re-running with nothing changed should be a no-op:
+					// no new commit, and no new push.
+					_, err := imageAutoReconciler.Reconcile(logr.NewContext(context.TODO(), ctrl.Log), ctrl.Request{
+						NamespacedName: updateKey,
+					})
+					Expect(err).To(BeNil())
+
+					// bring the local clone up to date, so that a push
+					// by the second run would show up in its HEAD
+					working, err := localRepo.Worktree()
+					Expect(err).ToNot(HaveOccurred())
+					if err := working.Pull(&git.PullOptions{ReferenceName: plumbing.NewBranchReferenceName(branch)}); err != nil {
+						Expect(err).To(Equal(git.NoErrAlreadyUpToDate))
+					}
+					head, _ = localRepo.Head()
+					Expect(head.Hash()).To(Equal(firstRun))
+
+					// now change the policy, and expect a new run to push a
+					// new commit
+					Expect(k8sClient.Get(context.Background(), policyKey, policy)).To(Succeed())
+					policy.Status.LatestImage = evenLatestImage
+					Expect(k8sClient.Status().Update(context.Background(), policy)).To(Succeed())
+
+					_, err = imageAutoReconciler.Reconcile(logr.NewContext(context.TODO(), ctrl.Log), ctrl.Request{
+						NamespacedName: updateKey,
+					})
+					Expect(err).To(BeNil())
+
+					waitForNewHead(localRepo, branch)
+					head, _ = localRepo.Head()
+					Expect(head.Hash()).ToNot(Equal(firstRun))
+				})
+
 				It("stops updating when suspended", func() {
 					// suspend it, and check that reconciliation does not run
 					var updatePatch imagev1.ImageUpdateAutomation
@@ -349,6 +390,565 @@ var _ = Describe("ImageUpdateAutomation", func() {
 					Expect(updateBySetters.Status.LastAutomationRunTime).ToNot(BeNil())
 				})
 			})
+
+			Context("with a commit message template", func() {
+
+				var (
+					updateKey types.NamespacedName
+					updateTpl *imagev1.ImageUpdateAutomation
+				)
+
+				BeforeEach(func() {
+					commitInRepo(cloneLocalRepoURL, branch, "Install setter marker", func(tmp string) {
+						replaceMarker(tmp, policyKey)
+					})
+					waitForNewHead(localRepo, branch)
+
+					updateKey = types.NamespacedName{
+						Namespace: gitRepoKey.Namespace,
+						Name:      "update-" + randStringRunes(5),
+					}
+				})
+
+				AfterEach(func() {
+					Expect(k8sClient.Delete(context.Background(), updateTpl)).To(Succeed())
+				})
+
+				It("renders the updated images into the commit message", func() {
+					updateTpl = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: "Update images\n\n" +
+									"{{ range .Updated.Images }}{{ .Name }}:{{ .NewTag }}\n{{ end }}",
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateTpl)).To(Succeed())
+					waitForNewHead(localRepo, branch)
+
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(ContainSubstring("helloworld"))
+					Expect(commit.Message).To(ContainSubstring("1.0.1"))
+				})
+
+				It("fails with Ready=False when the template is broken", func() {
+					updateTpl = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								// unterminated action: a parse error
+								MessageTemplate: "Update images\n\n{{ .Updated.Images",
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateTpl)).To(Succeed())
+
+					var newObj imagev1.ImageUpdateAutomation
+					Eventually(func() bool {
+						if err := k8sClient.Get(context.Background(), updateKey, &newObj); err != nil {
+							return false
+						}
+						rc := apimeta.FindStatusCondition(newObj.Status.Conditions, meta.ReadyCondition)
+						return rc != nil && rc.Status == metav1.ConditionFalse && rc.Reason == "InvalidCommitMessageTemplate"
+					}, timeout, time.Second).Should(BeTrue())
+
+					// no commit should have been made on the back of a
+					// template that can't be rendered
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal("Install setter marker"))
+				})
+
+				It("renders an unresolved field as <no value> by default", func() {
+					updateTpl = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								// .Updated.Bogus and .AutomationObject.Bogus don't exist
+								MessageTemplate: "Update images: {{ .Updated.Bogus }} {{ .AutomationObject.Bogus }}",
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateTpl)).To(Succeed())
+					waitForNewHead(localRepo, branch)
+
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal("Update images: <no value> <no value>"))
+				})
+
+				It("fails with Ready=False when MessageTemplateStrict is set and a field is unresolved", func() {
+					updateTpl = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								// .Updated.Bogus doesn't exist
+								MessageTemplate:       "Update images: {{ .Updated.Bogus }}",
+								MessageTemplateStrict: true,
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateTpl)).To(Succeed())
+
+					var newObj imagev1.ImageUpdateAutomation
+					Eventually(func() bool {
+						if err := k8sClient.Get(context.Background(), updateKey, &newObj); err != nil {
+							return false
+						}
+						rc := apimeta.FindStatusCondition(newObj.Status.Conditions, meta.ReadyCondition)
+						return rc != nil && rc.Status == metav1.ConditionFalse && rc.Reason == "InvalidCommitMessageTemplate"
+					}, timeout, time.Second).Should(BeTrue())
+
+					// no commit should have been made on the back of a
+					// template that can't be rendered
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal("Install setter marker"))
+				})
+			})
+
+			Context("with Kustomize", func() {
+
+				var (
+					updateKey         types.NamespacedName
+					updateByKustomize *imagev1.ImageUpdateAutomation
+				)
+
+				BeforeEach(func() {
+					// Replace the setter-based fixture with the
+					// kustomize-based one, substituting in the real
+					// (randomised) policy name before committing.
+					commitInRepo(cloneLocalRepoURL, branch, "Install kustomization", func(tmp string) {
+						Expect(copy.Copy("testdata/appconfig-kustomize", tmp)).To(Succeed())
+						replaceKustomizeMarker(tmp, policyKey)
+					})
+					waitForNewHead(localRepo, branch)
+
+					updateKey = types.NamespacedName{
+						Namespace: gitRepoKey.Namespace,
+						Name:      "update-" + randStringRunes(5),
+					}
+					updateByKustomize = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour}, // this is to ensure any subsequent run should be outside the scope of the testing
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategyKustomize,
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: commitMessage,
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateByKustomize)).To(Succeed())
+					waitForNewHead(localRepo, branch)
+				})
+
+				AfterEach(func() {
+					Expect(k8sClient.Delete(context.Background(), updateByKustomize)).To(Succeed())
+				})
+
+				It("updates the image in the kustomization", func() {
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal(commitMessage))
+
+					compareRepoWithExpected(cloneLocalRepoURL, branch, "testdata/appconfig-kustomize-expected", func(tmp string) {
+						replaceKustomizeMarker(tmp, policyKey)
+					})
+
+					var newObj imagev1.ImageUpdateAutomation
+					Expect(k8sClient.Get(context.Background(), updateKey, &newObj)).To(Succeed())
+					Expect(newObj.Status.LastRunResult).ToNot(BeNil())
+					Expect(newObj.Status.LastRunResult.ChangedFiles).To(ContainElement(ContainSubstring("kustomization.yaml")))
+				})
+			})
+
+			Context("with an ImagePolicySelector", func() {
+
+				var (
+					updateKey    types.NamespacedName
+					updateSelect *imagev1.ImageUpdateAutomation
+				)
+
+				BeforeEach(func() {
+					commitInRepo(cloneLocalRepoURL, branch, "Install setter marker", func(tmp string) {
+						replaceMarker(tmp, policyKey)
+					})
+					waitForNewHead(localRepo, branch)
+
+					Expect(k8sClient.Get(context.Background(), policyKey, policy)).To(Succeed())
+					policy.ObjectMeta.Labels = map[string]string{"in-scope": "yes"}
+					Expect(k8sClient.Update(context.Background(), policy)).To(Succeed())
+
+					updateKey = types.NamespacedName{
+						Namespace: gitRepoKey.Namespace,
+						Name:      "update-" + randStringRunes(5),
+					}
+				})
+
+				AfterEach(func() {
+					Expect(k8sClient.Delete(context.Background(), updateSelect)).To(Succeed())
+				})
+
+				It("leaves images from policies the selector excludes untouched", func() {
+					updateSelect = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							ImagePolicySelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"in-scope": "no"},
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: commitMessage,
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateSelect)).To(Succeed())
+
+					Eventually(func() bool {
+						var newObj imagev1.ImageUpdateAutomation
+						if err := k8sClient.Get(context.Background(), updateKey, &newObj); err != nil {
+							return false
+						}
+						return newObj.Status.LastAutomationRunTime != nil
+					}, timeout, time.Second).Should(BeTrue())
+
+					// the policy in scope of the marker was excluded by
+					// the selector, so no commit should have been made
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal("Install setter marker"))
+				})
+
+				It("updates images from policies the selector includes", func() {
+					updateSelect = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							ImagePolicySelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"in-scope": "yes"},
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: commitMessage,
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateSelect)).To(Succeed())
+					waitForNewHead(localRepo, branch)
+
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal(commitMessage))
+				})
+			})
+
+			Context("with Push and PullRequest", func() {
+
+				var (
+					updateKey     types.NamespacedName
+					updateByPush  *imagev1.ImageUpdateAutomation
+					pushBranch    string
+					prServer      *httptest.Server
+					prRequestsGot []string
+					tokenSecret   *corev1.Secret
+				)
+
+				BeforeEach(func() {
+					commitInRepo(cloneLocalRepoURL, branch, "Install setter marker", func(tmp string) {
+						replaceMarker(tmp, policyKey)
+					})
+					waitForNewHead(localRepo, branch)
+
+					pushBranch = branch + "-image-updates"
+					prRequestsGot = nil
+					prServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						prRequestsGot = append(prRequestsGot, r.URL.Path)
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusCreated)
+						w.Write([]byte(`{"html_url": "https://example.com/pr/1", "number": 1, "state": "open"}`))
+					}))
+
+					tokenSecret = &corev1.Secret{
+						StringData: map[string]string{"token": "abc123"},
+					}
+					tokenSecret.Name = "pr-token-" + randStringRunes(5)
+					tokenSecret.Namespace = namespace.Name
+					Expect(k8sClient.Create(context.Background(), tokenSecret)).To(Succeed())
+
+					updateKey = types.NamespacedName{
+						Namespace: gitRepoKey.Namespace,
+						Name:      "update-" + randStringRunes(5),
+					}
+					updateByPush = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: commitMessage,
+							},
+							Push: &imagev1.PushSpec{
+								Branch: pushBranch,
+								PullRequest: &imagev1.PullRequestSpec{
+									Provider: imagev1.PullRequestProviderGitHub,
+									Address:  prServer.URL,
+									SecretRef: meta.LocalObjectReference{
+										Name: tokenSecret.Name,
+									},
+								},
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateByPush)).To(Succeed())
+
+					Eventually(func() bool {
+						var newObj imagev1.ImageUpdateAutomation
+						if err := k8sClient.Get(context.Background(), updateKey, &newObj); err != nil {
+							return false
+						}
+						return newObj.Status.LastPullRequest != nil
+					}, timeout, time.Second).Should(BeTrue())
+				})
+
+				AfterEach(func() {
+					Expect(k8sClient.Delete(context.Background(), updateByPush)).To(Succeed())
+					Expect(k8sClient.Delete(context.Background(), tokenSecret)).To(Succeed())
+					prServer.Close()
+				})
+
+				It("leaves the tracked branch untouched and pushes the update to the side branch", func() {
+					// bring the local clone up to date, so that a push
+					// to the tracked branch would show up in its HEAD
+					working, err := localRepo.Worktree()
+					Expect(err).ToNot(HaveOccurred())
+					if err := working.Pull(&git.PullOptions{ReferenceName: plumbing.NewBranchReferenceName(branch)}); err != nil {
+						Expect(err).To(Equal(git.NoErrAlreadyUpToDate))
+					}
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).ToNot(Equal(commitMessage))
+
+					tmp, err := ioutil.TempDir("", "gotest-imageauto-push")
+					Expect(err).ToNot(HaveOccurred())
+					defer os.RemoveAll(tmp)
+					sideRepo, err := git.PlainClone(tmp, false, &git.CloneOptions{
+						URL:           cloneLocalRepoURL,
+						ReferenceName: plumbing.NewBranchReferenceName(pushBranch),
+					})
+					Expect(err).ToNot(HaveOccurred())
+					sideHead, err := sideRepo.Head()
+					Expect(err).ToNot(HaveOccurred())
+					sideCommit, err := sideRepo.CommitObject(sideHead.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sideCommit.Message).To(Equal(commitMessage))
+
+					var newObj imagev1.ImageUpdateAutomation
+					Expect(k8sClient.Get(context.Background(), updateKey, &newObj)).To(Succeed())
+					Expect(newObj.Status.LastPushBranch).To(Equal(pushBranch))
+					Expect(newObj.Status.LastPullRequest).ToNot(BeNil())
+					Expect(newObj.Status.LastPullRequest.URL).To(Equal("https://example.com/pr/1"))
+					Expect(newObj.Status.LastPullRequest.Number).To(Equal(1))
+
+					Expect(prRequestsGot).ToNot(BeEmpty())
+				})
+			})
+
+			Context("with signed commits", func() {
+
+				var (
+					updateKey        types.NamespacedName
+					updateSigned     *imagev1.ImageUpdateAutomation
+					signingKeySecret *corev1.Secret
+					pgpEntity        *openpgp.Entity
+				)
+
+				BeforeEach(func() {
+					commitInRepo(cloneLocalRepoURL, branch, "Install setter marker", func(tmp string) {
+						replaceMarker(tmp, policyKey)
+					})
+					waitForNewHead(localRepo, branch)
+
+					var err error
+					pgpEntity, err = openpgp.NewEntity("image-automation-test", "", "test@example.com", nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					var privateKey bytes.Buffer
+					w, err := armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(pgpEntity.SerializePrivate(w, nil)).To(Succeed())
+					Expect(w.Close()).To(Succeed())
+
+					signingKeySecret = &corev1.Secret{
+						Data: map[string][]byte{
+							"git.asc": privateKey.Bytes(),
+						},
+					}
+					signingKeySecret.Name = "signing-key-" + randStringRunes(5)
+					signingKeySecret.Namespace = namespace.Name
+					Expect(k8sClient.Create(context.Background(), signingKeySecret)).To(Succeed())
+
+					updateKey = types.NamespacedName{
+						Namespace: gitRepoKey.Namespace,
+						Name:      "update-" + randStringRunes(5),
+					}
+					updateSigned = &imagev1.ImageUpdateAutomation{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      updateKey.Name,
+							Namespace: updateKey.Namespace,
+						},
+						Spec: imagev1.ImageUpdateAutomationSpec{
+							Interval: metav1.Duration{Duration: 2 * time.Hour},
+							Checkout: imagev1.GitCheckoutSpec{
+								GitRepositoryRef: meta.LocalObjectReference{
+									Name: gitRepoKey.Name,
+								},
+								Branch: branch,
+							},
+							Update: &imagev1.UpdateStrategy{
+								Strategy: imagev1.UpdateStrategySetters,
+							},
+							Commit: imagev1.CommitSpec{
+								MessageTemplate: commitMessage,
+								SigningKey: &meta.LocalObjectReference{
+									Name: signingKeySecret.Name,
+								},
+							},
+						},
+					}
+					Expect(k8sClient.Create(context.Background(), updateSigned)).To(Succeed())
+					waitForNewHead(localRepo, branch)
+				})
+
+				AfterEach(func() {
+					Expect(k8sClient.Delete(context.Background(), updateSigned)).To(Succeed())
+					Expect(k8sClient.Delete(context.Background(), signingKeySecret)).To(Succeed())
+				})
+
+				It("signs the commit it makes with the given key", func() {
+					head, _ := localRepo.Head()
+					commit, err := localRepo.CommitObject(head.Hash())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(commit.Message).To(Equal(commitMessage))
+					Expect(commit.PGPSignature).ToNot(BeEmpty())
+
+					var keyring bytes.Buffer
+					w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(pgpEntity.Serialize(w)).To(Succeed())
+					Expect(w.Close()).To(Succeed())
+
+					verifiedBy, err := commit.Verify(keyring.String())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(verifiedBy.PrimaryKey.KeyId).To(Equal(pgpEntity.PrimaryKey.KeyId))
+				})
+			})
 		}
 	}
 
@@ -361,12 +961,20 @@ var _ = Describe("ImageUpdateAutomation", func() {
 		})
 	})
 
-	Context("Using libgit2", func() {
-		Context("with HTTP", func() {
-			Describe("runs end to end", endToEnd(sourcev1.LibGit2Implementation, "http"))
-		})
-		Context("with SSH", func() {
-			Describe("runs end to end", endToEnd(sourcev1.LibGit2Implementation, "ssh"))
+	// Only go-git is implemented; a GitRepository configured for any
+	// other implementation (e.g. libgit2) is rejected rather than
+	// silently cloned with go-git regardless - see cloneAndCheckout.
+	Describe("cloneAndCheckout", func() {
+		It("rejects a GitRepository using an unsupported git implementation", func() {
+			r := &ImageUpdateAutomationReconciler{}
+			origin := &sourcev1.GitRepository{
+				Spec: sourcev1.GitRepositorySpec{
+					URL:               "https://example.com/owner/repo.git",
+					GitImplementation: sourcev1.LibGit2Implementation,
+				},
+			}
+			_, _, err := r.cloneAndCheckout(context.Background(), origin, "main", "", nil)
+			Expect(err).To(MatchError(ContainSubstring("not supported")))
 		})
 	})
 
@@ -434,6 +1042,16 @@ func setterRef(name types.NamespacedName) string {
 	return fmt.Sprintf(`{"%s": "%s:%s"}`, update.SetterShortHand, name.Namespace, name.Name)
 }
 
+func replaceKustomizeMarker(path string, policyKey types.NamespacedName) {
+	// NB this requires knowledge of what's in the git
+	// repo, so a little brittle
+	kustomization := filepath.Join(path, "kustomization.yaml")
+	filebytes, err := ioutil.ReadFile(kustomization)
+	Expect(err).NotTo(HaveOccurred())
+	newfilebytes := bytes.ReplaceAll(filebytes, []byte("KUSTOMIZE_POLICY_SITE"), []byte(setterRef(policyKey)))
+	Expect(ioutil.WriteFile(kustomization, newfilebytes, os.FileMode(0666))).To(Succeed())
+}
+
 func waitForNewHead(repo *git.Repository, branch string) {
 	head, _ := repo.Head()
 	headHash := head.Hash().String()
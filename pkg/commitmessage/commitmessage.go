@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package commitmessage renders the commit (and pull request)
+// message templates used by ImageUpdateAutomation, giving them a
+// typed view of what an update strategy actually changed.
+package commitmessage
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// imageUpdate describes a single image reference that was updated.
+type imageUpdate struct {
+	Name   string
+	OldTag string
+	NewTag string
+	Policy string
+}
+
+// funcs are the template helpers available alongside the
+// "text/template" built-ins: a small, explicit subset of what sprig
+// provides, rather than pulling in the whole library for three
+// functions.
+var funcs = template.FuncMap{
+	"trim":  strings.TrimSpace,
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+}
+
+// Render parses tmpl as a "text/template" and executes it against a
+// context built from the given automation object and the changes
+// recorded by an update strategy: `.AutomationObject` (the
+// namespaced name of the ImageUpdateAutomation), `.Updated.Images`
+// (one entry per distinct image update, each with `.Name`, `.OldTag`,
+// `.NewTag` and `.Policy`), and `.Updated.Files` (the paths changed).
+// The context is built out of maps, rather than structs, so that
+// "missingkey" has something to act on: Go's text/template only
+// applies that option to map lookups, never to struct fields, which
+// always hard-error on an unknown one. If strict is true, the
+// template is required to resolve every field it references, via
+// "missingkey=error"; by default, an unresolved field renders
+// "<no value>" instead. A malformed template is always an error,
+// regardless of strict.
+func Render(tmpl string, automation types.NamespacedName, changes update.Result, strict bool) (string, error) {
+	t := template.New("commitmessage").Funcs(funcs)
+	if strict {
+		t = t.Option("missingkey=error")
+	}
+	t, err := t.Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing commit message template: %w", err)
+	}
+
+	data := map[string]interface{}{
+		"AutomationObject": map[string]interface{}{
+			"Namespace": automation.Namespace,
+			"Name":      automation.Name,
+		},
+		"Updated": map[string]interface{}{
+			"Images": imageUpdateMaps(changes),
+			"Files":  changes.Files(),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("executing commit message template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// imageUpdates reduces the changes recorded by an update strategy to
+// one entry per distinct image update, in the order they were first
+// seen.
+func imageUpdates(changes update.Result) []imageUpdate {
+	var images []imageUpdate
+	seen := map[imageUpdate]struct{}{}
+	for _, c := range changes.Changes {
+		img := imageUpdate{Name: c.Name, OldTag: c.OldTag, NewTag: c.NewTag, Policy: c.Policy}
+		if _, ok := seen[img]; ok {
+			continue
+		}
+		seen[img] = struct{}{}
+		images = append(images, img)
+	}
+	return images
+}
+
+// imageUpdateMaps renders each of imageUpdates as a map, rather than
+// a struct, so that a reference to one of its fields is subject to
+// the same "missingkey" handling as the rest of the template context.
+func imageUpdateMaps(changes update.Result) []map[string]interface{} {
+	var maps []map[string]interface{}
+	for _, u := range imageUpdates(changes) {
+		maps = append(maps, map[string]interface{}{
+			"Name":   u.Name,
+			"OldTag": u.OldTag,
+			"NewTag": u.NewTag,
+			"Policy": u.Policy,
+		})
+	}
+	return maps
+}
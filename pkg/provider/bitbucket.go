@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// bitbucketClient talks to the Bitbucket Cloud REST API (v2) to
+// open, or update, a pull request.
+type bitbucketClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type bitbucketBranchRef struct {
+	Name string `json:"name"`
+}
+
+type bitbucketPullRequest struct {
+	ID    int    `json:"id"`
+	State string `json:"state"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Source struct {
+		Branch bitbucketBranchRef `json:"branch"`
+	} `json:"source"`
+	Destination struct {
+		Branch bitbucketBranchRef `json:"branch"`
+	} `json:"destination"`
+}
+
+type bitbucketPullRequestList struct {
+	Values []bitbucketPullRequest `json:"values"`
+}
+
+type bitbucketReviewer struct {
+	Username string `json:"username"`
+}
+
+type bitbucketPullRequestBody struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Source      *bitbucketEndpoint  `json:"source,omitempty"`
+	Destination *bitbucketEndpoint  `json:"destination,omitempty"`
+	Reviewers   []bitbucketReviewer `json:"reviewers,omitempty"`
+}
+
+type bitbucketEndpoint struct {
+	Branch bitbucketBranchRef `json:"branch"`
+}
+
+func (c *bitbucketClient) CreateOrUpdatePullRequest(req Request) (Result, error) {
+	existing, err := c.findOpenPullRequest(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var reviewers []bitbucketReviewer
+	for _, r := range req.Reviewers {
+		reviewers = append(reviewers, bitbucketReviewer{Username: r})
+	}
+
+	body := bitbucketPullRequestBody{
+		Title:       req.Title,
+		Description: req.Body,
+		Source:      &bitbucketEndpoint{Branch: bitbucketBranchRef{Name: req.SourceBranch}},
+		Destination: &bitbucketEndpoint{Branch: bitbucketBranchRef{Name: req.TargetBranch}},
+		Reviewers:   reviewers,
+	}
+
+	method, url := http.MethodPost, fmt.Sprintf("%s/repositories/%s/%s/pullrequests", c.baseURL, req.Owner, req.Repo)
+	if existing != 0 {
+		method, url = http.MethodPut, fmt.Sprintf("%s/repositories/%s/%s/pullrequests/%d", c.baseURL, req.Owner, req.Repo, existing)
+	}
+
+	var pull bitbucketPullRequest
+	if err := doJSON(c.http, method, url, "Authorization", "Bearer "+req.Token, body, []int{http.StatusOK, http.StatusCreated}, &pull); err != nil {
+		return Result{}, err
+	}
+
+	return Result{URL: pull.Links.HTML.Href, Number: pull.ID, State: pull.State}, nil
+}
+
+// findOpenPullRequest looks for an open pull request from
+// req.SourceBranch into req.TargetBranch, returning its ID, or 0 if
+// none is open.
+func (c *bitbucketClient) findOpenPullRequest(req Request) (int, error) {
+	url := fmt.Sprintf("%s/repositories/%s/%s/pullrequests?state=OPEN", c.baseURL, req.Owner, req.Repo)
+
+	var list bitbucketPullRequestList
+	listOpenRequests(c.http, url, "Authorization", "Bearer "+req.Token, &list)
+	for _, p := range list.Values {
+		if p.Source.Branch.Name == req.SourceBranch && p.Destination.Branch.Name == req.TargetBranch {
+			return p.ID, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// giteaClient talks to the Gitea REST API to open, or update, a pull
+// request.
+type giteaClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type giteaPullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+	State  string `json:"state"`
+	Head   struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+type giteaPullRequestBody struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+	Head  string `json:"head,omitempty"`
+	Base  string `json:"base,omitempty"`
+}
+
+type giteaReviewersBody struct {
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+func (c *giteaClient) CreateOrUpdatePullRequest(req Request) (Result, error) {
+	existing, err := c.findOpenPullRequest(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	body := giteaPullRequestBody{
+		Title: req.Title,
+		Body:  req.Body,
+		Head:  req.SourceBranch,
+		Base:  req.TargetBranch,
+	}
+
+	method, url := http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/pulls", c.baseURL, req.Owner, req.Repo)
+	if existing != 0 {
+		method, url = http.MethodPatch, fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, req.Owner, req.Repo, existing)
+	}
+
+	var pull giteaPullRequest
+	if err := doJSON(c.http, method, url, "Authorization", "token "+req.Token, body, []int{http.StatusOK, http.StatusCreated}, &pull); err != nil {
+		return Result{}, err
+	}
+
+	if len(req.Reviewers) > 0 {
+		if err := c.requestReviewers(req, pull.Number); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{URL: pull.URL, Number: pull.Number, State: pull.State}, nil
+}
+
+// requestReviewers asks Gitea to request a review from each of
+// req.Reviewers on the given pull request. Reviewers are a distinct
+// concept from assignees, and aren't settable via the create/update
+// pull request body; since a previous run's request isn't
+// automatically reapplied, this is called on every create or update
+// rather than only on create.
+func (c *giteaClient) requestReviewers(req Request, number int) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", c.baseURL, req.Owner, req.Repo, number)
+	body := giteaReviewersBody{Reviewers: req.Reviewers}
+	return doJSON(c.http, http.MethodPost, url, "Authorization", "token "+req.Token, body, []int{http.StatusOK, http.StatusCreated}, nil)
+}
+
+// findOpenPullRequest looks for an open pull request from
+// req.SourceBranch into req.TargetBranch, returning its number, or 0
+// if none is open.
+func (c *giteaClient) findOpenPullRequest(req Request) (int, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?state=open", c.baseURL, req.Owner, req.Repo)
+
+	var pulls []giteaPullRequest
+	listOpenRequests(c.http, url, "Authorization", "token "+req.Token, &pulls)
+	for _, p := range pulls {
+		if p.Head.Ref == req.SourceBranch && p.Base.Ref == req.TargetBranch {
+			return p.Number, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// githubClient talks to the GitHub REST API (and API-compatible
+// GitHub Enterprise instances) to open, or update, a pull request.
+type githubClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type githubPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"`
+}
+
+type githubPullRequestBody struct {
+	Title  string   `json:"title,omitempty"`
+	Body   string   `json:"body,omitempty"`
+	Head   string   `json:"head,omitempty"`
+	Base   string   `json:"base,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+type githubReviewersBody struct {
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+func (c *githubClient) CreateOrUpdatePullRequest(req Request) (Result, error) {
+	existing, err := c.findOpenPullRequest(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	body := githubPullRequestBody{
+		Title:  req.Title,
+		Body:   req.Body,
+		Head:   req.SourceBranch,
+		Base:   req.TargetBranch,
+		Labels: req.Labels,
+	}
+
+	method, url := http.MethodPost, fmt.Sprintf("%s/repos/%s/%s/pulls", c.baseURL, req.Owner, req.Repo)
+	if existing != 0 {
+		method, url = http.MethodPatch, fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, req.Owner, req.Repo, existing)
+	}
+
+	var pull githubPullRequest
+	if err := doJSON(c.http, method, url, "Authorization", "token "+req.Token, body, []int{http.StatusOK, http.StatusCreated}, &pull); err != nil {
+		return Result{}, err
+	}
+
+	if len(req.Reviewers) > 0 {
+		if err := c.requestReviewers(req, pull.Number); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{URL: pull.HTMLURL, Number: pull.Number, State: pull.State}, nil
+}
+
+// requestReviewers asks GitHub to request a review from each of
+// req.Reviewers on the given pull request. The create/update pull
+// request body has no field for this - GitHub only accepts reviewers
+// via this dedicated endpoint - and a previously requested reviewer
+// isn't remembered, so this is called on every create or update
+// rather than only on create.
+func (c *githubClient) requestReviewers(req Request, number int) error {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/requested_reviewers", c.baseURL, req.Owner, req.Repo, number)
+	body := githubReviewersBody{Reviewers: req.Reviewers}
+	return doJSON(c.http, http.MethodPost, url, "Authorization", "token "+req.Token, body, []int{http.StatusOK, http.StatusCreated}, nil)
+}
+
+// findOpenPullRequest looks for an open pull request from
+// req.SourceBranch into req.TargetBranch, returning its number, or 0
+// if none is open.
+func (c *githubClient) findOpenPullRequest(req Request) (int, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls?head=%s:%s&base=%s&state=open",
+		c.baseURL, req.Owner, req.Repo, req.Owner, req.SourceBranch, req.TargetBranch)
+
+	var pulls []githubPullRequest
+	listOpenRequests(c.http, url, "Authorization", "token "+req.Token, &pulls)
+	if len(pulls) == 0 {
+		return 0, nil
+	}
+	return pulls[0].Number, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlabClient talks to the GitLab REST API to open, or update, a
+// merge request.
+type gitlabClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	State  string `json:"state"`
+}
+
+type gitlabMergeRequestBody struct {
+	SourceBranch string `json:"source_branch,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	Labels       string `json:"labels,omitempty"`
+	ReviewerIDs  []int  `json:"reviewer_ids,omitempty"`
+}
+
+type gitlabUser struct {
+	ID int `json:"id"`
+}
+
+func (c *gitlabClient) CreateOrUpdatePullRequest(req Request) (Result, error) {
+	project := url.QueryEscape(req.Owner + "/" + req.Repo)
+
+	existing, err := c.findOpenMergeRequest(project, req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	reviewerIDs, err := c.resolveReviewerIDs(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	body := gitlabMergeRequestBody{
+		SourceBranch: req.SourceBranch,
+		TargetBranch: req.TargetBranch,
+		Title:        req.Title,
+		Description:  req.Body,
+		Labels:       strings.Join(req.Labels, ","),
+		ReviewerIDs:  reviewerIDs,
+	}
+
+	method, reqURL := http.MethodPost, fmt.Sprintf("%s/projects/%s/merge_requests", c.baseURL, project)
+	if existing != 0 {
+		method, reqURL = http.MethodPut, fmt.Sprintf("%s/projects/%s/merge_requests/%d", c.baseURL, project, existing)
+	}
+
+	var mr gitlabMergeRequest
+	if err := doJSON(c.http, method, reqURL, "PRIVATE-TOKEN", req.Token, body, []int{http.StatusOK, http.StatusCreated}, &mr); err != nil {
+		return Result{}, err
+	}
+
+	return Result{URL: mr.WebURL, Number: mr.IID, State: mr.State}, nil
+}
+
+// resolveReviewerIDs looks up the numeric GitLab user ID for each of
+// req.Reviewers (given as usernames, per PullRequestSpec.Reviewers),
+// since the merge request's reviewer_ids field takes IDs, not
+// usernames.
+func (c *gitlabClient) resolveReviewerIDs(req Request) ([]int, error) {
+	var ids []int
+	for _, username := range req.Reviewers {
+		reqURL := fmt.Sprintf("%s/users?username=%s", c.baseURL, url.QueryEscape(username))
+
+		var users []gitlabUser
+		if err := doJSON(c.http, http.MethodGet, reqURL, "PRIVATE-TOKEN", req.Token, nil, []int{http.StatusOK}, &users); err != nil {
+			return nil, fmt.Errorf("looking up GitLab user %q: %w", username, err)
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("no GitLab user found for reviewer %q", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+// findOpenMergeRequest looks for an open merge request from
+// req.SourceBranch into req.TargetBranch, returning its IID, or 0 if
+// none is open.
+func (c *gitlabClient) findOpenMergeRequest(project string, req Request) (int, error) {
+	reqURL := fmt.Sprintf("%s/projects/%s/merge_requests?source_branch=%s&target_branch=%s&state=opened",
+		c.baseURL, project, req.SourceBranch, req.TargetBranch)
+
+	var mrs []gitlabMergeRequest
+	listOpenRequests(c.http, reqURL, "PRIVATE-TOKEN", req.Token, &mrs)
+	if len(mrs) == 0 {
+		return 0, nil
+	}
+	return mrs[0].IID, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provider implements minimal clients for opening pull/merge
+// requests against the Git hosting providers supported by the
+// ImageUpdateAutomation's Push.PullRequest spec.
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Name identifies a supported Git hosting provider.
+type Name string
+
+const (
+	GitHub    Name = "github"
+	GitLab    Name = "gitlab"
+	Gitea     Name = "gitea"
+	Bitbucket Name = "bitbucket"
+)
+
+// Request carries the parameters needed to open, or update, a
+// pull/merge request.
+type Request struct {
+	// Owner/Repo identify the repository the pull request targets.
+	Owner, Repo string
+	// SourceBranch is the branch carrying the change.
+	SourceBranch string
+	// TargetBranch is the branch the change should be merged into.
+	TargetBranch string
+	Title        string
+	Body         string
+	Labels       []string
+	Reviewers    []string
+	// Token authenticates the request against the provider's API.
+	Token string
+}
+
+// Result is what was created (or found to already exist) for a
+// SourceBranch -> TargetBranch pair.
+type Result struct {
+	URL    string
+	Number int
+	State  string
+}
+
+// Client opens, or updates, a pull/merge request with a provider.
+type Client interface {
+	CreateOrUpdatePullRequest(req Request) (Result, error)
+}
+
+// NewClient returns a Client for the named provider, talking to the
+// API at baseURL.
+func NewClient(name Name, baseURL string) (Client, error) {
+	switch name {
+	case GitHub:
+		return &githubClient{baseURL: baseURL, http: http.DefaultClient}, nil
+	case GitLab:
+		return &gitlabClient{baseURL: baseURL, http: http.DefaultClient}, nil
+	case Gitea:
+		return &giteaClient{baseURL: baseURL, http: http.DefaultClient}, nil
+	case Bitbucket:
+		return &bitbucketClient{baseURL: baseURL, http: http.DefaultClient}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q", name)
+	}
+}
+
+// listOpenRequests performs a best-effort GET against listURL,
+// authenticating with authHeader/authValue, and decodes the JSON
+// response into out (a pointer to a slice, or to a struct for a
+// provider that wraps its list in an object). Listing is
+// best-effort: if the request fails, out is left as its zero value,
+// so the caller falls back to creating a new pull/merge request
+// rather than blocking the automation on a failed listing.
+func listOpenRequests(client *http.Client, listURL, authHeader, authValue string, out interface{}) {
+	_ = doJSON(client, http.MethodGet, listURL, authHeader, authValue, nil, []int{http.StatusOK}, out)
+}
+
+// doJSON performs an HTTP request with the given method, URL and
+// authorization header value, marshalling body (if non-nil) as the
+// JSON request body, and unmarshalling the JSON response into out
+// (if non-nil). A response status outside wantStatus is treated as
+// an error.
+func doJSON(client *http.Client, method, url, authHeader, authValue string, body interface{}, wantStatus []int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authValue != "" {
+		req.Header.Set(authHeader, authValue)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	ok := false
+	for _, s := range wantStatus {
+		if resp.StatusCode == s {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: unexpected status %s", method, url, resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test holds helpers shared between the test suites in this
+// repository.
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/gomega"
+)
+
+// ExpectMatchingDirectories asserts that the directory trees rooted
+// at gotPath and wantPath contain the same set of files, with the
+// same contents (ignoring the .git directory).
+func ExpectMatchingDirectories(gotPath, wantPath string) {
+	got := listFiles(gotPath)
+	want := listFiles(wantPath)
+
+	Expect(got).To(Equal(want), "file lists differ between %s and %s", gotPath, wantPath)
+
+	for rel := range want {
+		gotBytes, err := ioutil.ReadFile(filepath.Join(gotPath, rel))
+		Expect(err).ToNot(HaveOccurred())
+		wantBytes, err := ioutil.ReadFile(filepath.Join(wantPath, rel))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(gotBytes)).To(Equal(string(wantBytes)), "contents differ for %s", rel)
+	}
+}
+
+func listFiles(root string) map[string]struct{} {
+	files := map[string]struct{}{}
+	filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+			return nil
+		}
+		files[rel] = struct{}{}
+		return nil
+	})
+	return files
+}
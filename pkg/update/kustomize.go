@@ -0,0 +1,201 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// imagePolicyAnnotationRe matches the same `$imagepolicy` marker used
+// by the Setters strategy, but attached as a line comment on an
+// `images:` entry's `name` field, so that a kustomization.yaml can
+// pin a particular entry to a policy regardless of whether its name
+// matches the policy's image name, e.g.:
+//
+//	images:
+//	  - name: helloworld # {"$imagepolicy": "automation-ns:my-policy"}
+var imagePolicyAnnotationRe = regexp.MustCompile(`"` + regexp.QuoteMeta(SetterShortHand) + `"\s*:\s*"([^"]+)"`)
+
+// UpdateWithKustomize visits each kustomization.yaml under path
+// (recursively) and, for every entry under its `images:` field that
+// carries a `$imagepolicy` annotation comment naming one of the given
+// policies (keyed by "namespace:name"), sets `newName`/`newTag` to
+// match that policy's latest image. Editing is done on the parsed
+// yaml.RNode tree, so field ordering and comments elsewhere in the
+// file are preserved. It returns a record of every change made, with
+// each Change.Path given relative to repoRoot.
+func UpdateWithKustomize(repoRoot, path string, policies map[string]string) (Result, error) {
+	result := Result{}
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Base(p) != "kustomization.yaml" {
+			return nil
+		}
+
+		original, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		node, err := yaml.Parse(string(original))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+
+		images, err := node.Pipe(yaml.Lookup("images"))
+		if err != nil {
+			return err
+		}
+		if images == nil {
+			return nil
+		}
+
+		elements, err := images.Elements()
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		for _, image := range elements {
+			ref, ok := policyRefForImage(image, policies)
+			if !ok {
+				continue
+			}
+
+			oldName, oldTag := currentNewNameAndTag(image)
+			newName, newTag := splitImage(policies[ref])
+			if (newName == "" || newName == oldName) && (newTag == "" || newTag == oldTag) {
+				continue
+			}
+			if newTag != "" {
+				if err := setField(image, "newTag", newTag); err != nil {
+					return err
+				}
+			}
+			if newName != "" {
+				if err := setField(image, "newName", newName); err != nil {
+					return err
+				}
+			}
+
+			changed = true
+			name := newName
+			if name == "" {
+				name, _ = currentNewNameAndTag(image)
+			}
+			relPath, err := filepath.Rel(repoRoot, p)
+			if err != nil {
+				return err
+			}
+			result.Changes = append(result.Changes, Change{
+				Policy: ref,
+				Name:   name,
+				OldTag: oldTag,
+				NewTag: newTag,
+				Path:   relPath,
+			})
+		}
+
+		if !changed {
+			return nil
+		}
+
+		out, err := node.String()
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(p, []byte(out), info.Mode())
+	})
+
+	return result, err
+}
+
+// policyRefForImage looks at the `name` field of an entry under
+// `images:` and, if it carries a `$imagepolicy` annotation comment
+// naming one of the given policies, returns that policy's
+// "namespace:name" key. An entry without the annotation - or whose
+// annotation names a policy not in scope - is left untouched, the
+// same way an unmarked line is left untouched by the Setters
+// strategy.
+func policyRefForImage(image *yaml.RNode, policies map[string]string) (string, bool) {
+	nameNode, err := image.Pipe(yaml.Lookup("name"))
+	if err != nil || nameNode == nil {
+		return "", false
+	}
+
+	ref, ok := policyRefFromAnnotation(nameNode)
+	if !ok {
+		return "", false
+	}
+	_, known := policies[ref]
+	return ref, known
+}
+
+// policyRefFromAnnotation extracts the policy named by a
+// `$imagepolicy` marker in the line comment on an `images:` entry's
+// `name` field, if present.
+func policyRefFromAnnotation(nameNode *yaml.RNode) (string, bool) {
+	comment := nameNode.YNode().LineComment
+	if comment == "" {
+		return "", false
+	}
+	m := imagePolicyAnnotationRe.FindStringSubmatch(comment)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// currentNewNameAndTag reads the `newName` and `newTag` fields
+// already present on an `images:` entry, if any, so callers can tell
+// what a value was before it gets overwritten.
+func currentNewNameAndTag(image *yaml.RNode) (name, tag string) {
+	if nameNode, err := image.Pipe(yaml.Lookup("newName")); err == nil && nameNode != nil {
+		name = yaml.GetValue(nameNode)
+	}
+	if tagNode, err := image.Pipe(yaml.Lookup("newTag")); err == nil && tagNode != nil {
+		tag = yaml.GetValue(tagNode)
+	}
+	return name, tag
+}
+
+func setField(node *yaml.RNode, field, value string) error {
+	return node.PipeE(yaml.FieldSetter{
+		Name:  field,
+		Value: yaml.NewScalarRNode(value),
+	})
+}
+
+// splitImage splits an image ref of the form `name:tag` into its
+// name and tag parts.
+func splitImage(image string) (name, tag string) {
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 {
+		return image, ""
+	}
+	return image[:idx], image[idx+1:]
+}
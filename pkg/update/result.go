@@ -0,0 +1,52 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import "sort"
+
+// Change records a single image reference that was updated by an
+// update strategy: which ImagePolicy (keyed by "namespace:name")
+// caused the change, the image name, its tag before and after, and
+// the file it was changed in.
+type Change struct {
+	Policy string
+	Name   string
+	OldTag string
+	NewTag string
+	Path   string
+}
+
+// Result records every individual change made by an update
+// strategy.
+type Result struct {
+	Changes []Change
+}
+
+// Files returns the sorted, de-duplicated set of files touched by
+// the changes in r.
+func (r Result) Files() []string {
+	seen := map[string]struct{}{}
+	for _, c := range r.Changes {
+		seen[c.Path] = struct{}{}
+	}
+	files := make([]string, 0, len(seen))
+	for f := range seen {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
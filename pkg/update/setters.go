@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package update provides the strategies used to apply image policy
+// updates to files checked out from a git repository.
+package update
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// SetterShortHand is the shorthand comment tag used in manifests to
+// mark a field as updatable by an image policy, e.g.:
+//
+//	image: helloworld:v1.0.0 # {"$imagepolicy": "automation-ns:my-policy"}
+const SetterShortHand = "$imagepolicy"
+
+var setterRe = regexp.MustCompile(`^(\s*\S+:\s*)(\S+)(\s*#.*"` + regexp.QuoteMeta(SetterShortHand) + `"\s*:\s*"([^"]+)".*)$`)
+
+// UpdateWithSetters visits each YAML file under path (recursively)
+// and rewrites any image reference marked with the SetterShortHand
+// comment so that it matches the latest image given for the
+// referenced policy in policies (keyed by "namespace:name"). It
+// returns a record of every change made, with each Change.Path given
+// relative to repoRoot.
+func UpdateWithSetters(repoRoot, path string, policies map[string]string) (Result, error) {
+	result := Result{}
+
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(p)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		original, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		lines := splitLines(original)
+		for i, line := range lines {
+			m := setterRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			ref := m[4]
+			latest, ok := policies[ref]
+			if !ok || latest == "" {
+				continue
+			}
+			if m[2] == latest {
+				continue
+			}
+			lines[i] = fmt.Sprintf("%s%s%s", m[1], latest, line[len(m[1])+len(m[2]):])
+			changed = true
+			name, oldTag := splitImage(m[2])
+			_, newTag := splitImage(latest)
+			relPath, err := filepath.Rel(repoRoot, p)
+			if err != nil {
+				return err
+			}
+			result.Changes = append(result.Changes, Change{
+				Policy: ref,
+				Name:   name,
+				OldTag: oldTag,
+				NewTag: newTag,
+				Path:   relPath,
+			})
+		}
+
+		if changed {
+			return ioutil.WriteFile(p, joinLines(lines), info.Mode())
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+func splitLines(b []byte) []string {
+	s := string(b)
+	var lines []string
+	start := 0
+	for i, c := range s {
+		if c == '\n' {
+			lines = append(lines, s[start:i])
+			start = i + 1
+		}
+	}
+	lines = append(lines, s[start:])
+	return lines
+}
+
+func joinLines(lines []string) []byte {
+	out := ""
+	for i, l := range lines {
+		if i > 0 {
+			out += "\n"
+		}
+		out += l
+	}
+	return []byte(out)
+}